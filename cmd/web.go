@@ -1,16 +1,24 @@
 package cmd
 
 import (
+	"context"
 	"crypto/tls"
+	"crypto/x509"
 	"fmt"
+	"io/ioutil"
 	"net"
 	"net/http"
+	"net/http/pprof"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/codegangsta/cli"
 
 	"github.com/Unknwon/macaron"
 
+	"github.com/containerops/dockyard/middleware"
 	"github.com/containerops/dockyard/setting"
 	"github.com/containerops/dockyard/utils"
 	"github.com/containerops/dockyard/web"
@@ -39,23 +47,39 @@ func runWeb(c *cli.Context) {
 	m := macaron.New()
 
 	//Set Macaron Web Middleware And Routers
-	web.SetDockyardMacaron(m)
+	if err := web.SetDockyardMacaron(m); err != nil {
+		fmt.Printf("启动 dockyard 的 Web 服务错误: %v", err)
+		return
+	}
+
+	//pprof 使用独立的端口监听，不和 Web 服务暴露在一起
+	if setting.PprofEnable {
+		go runPprof()
+	}
+
+	server := newServer(m)
+
+	//根据监听模式设置启动服务的函数和出错时的提示
+	var serve func() error
+	var errmsg string
 
 	switch setting.ListenMode {
 	case "http":
-		listenaddr := fmt.Sprintf("%s:%d", c.String("address"), c.Int("port"))
-		if err := http.ListenAndServe(listenaddr, m); err != nil {
-			fmt.Printf("启动 dockyard 的 HTTP 服务错误: %v", err)
-		}
-		break
+		server.Addr = fmt.Sprintf("%s:%d", c.String("address"), c.Int("port"))
+		serve, errmsg = server.ListenAndServe, "启动 dockyard 的 HTTP 服务错误: %v"
 	case "https":
 		//HTTPS 强制使用 443 端口
-		listenaddr := fmt.Sprintf("%s:443", c.String("address"))
-		server := &http.Server{Addr: listenaddr, TLSConfig: &tls.Config{MinVersion: tls.VersionTLS10}, Handler: m}
-		if err := server.ListenAndServeTLS(setting.HttpsCertFile, setting.HttpsKeyFile); err != nil {
+		server.Addr = fmt.Sprintf("%s:443", c.String("address"))
+		tlsconfig, err := tlsConfig()
+		if err != nil {
 			fmt.Printf("启动 dockyard 的 HTTPS 服务错误: %v", err)
+			return
 		}
-		break
+		server.TLSConfig = tlsconfig
+		serve = func() error {
+			return server.ListenAndServeTLS(setting.HttpsCertFile, setting.HttpsKeyFile)
+		}
+		errmsg = "启动 dockyard 的 HTTPS 服务错误: %v"
 	case "unix":
 		listenaddr := fmt.Sprintf("%s", c.String("address"))
 		//如果存在 Unix Socket 文件就删除
@@ -63,16 +87,112 @@ func runWeb(c *cli.Context) {
 			os.Remove(listenaddr)
 		}
 
-		if listener, err := net.Listen("unix", listenaddr); err != nil {
+		listener, err := net.Listen("unix", listenaddr)
+		if err != nil {
 			fmt.Printf("启动 dockyard 的 Unix Socket 监听错误: %v", err)
-		} else {
-			server := &http.Server{Handler: m}
-			if err := server.Serve(listener); err != nil {
-				fmt.Printf("启动 dockyard 的 Unix Socket 监听错误: %v", err)
-			}
+			return
+		}
+		serve = func() error {
+			return server.Serve(listener)
 		}
-		break
+		errmsg = "启动 dockyard 的 Unix Socket 监听错误: %v"
 	default:
-		break
+		return
+	}
+
+	//收到 SIGTERM 或 SIGINT 时停止接收新的连接，等待处理中的 Request 完成后退出
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGTERM, syscall.SIGINT)
+
+	if err := serveGraceful(server, serve, quit); err != nil {
+		fmt.Printf(errmsg, err)
+	}
+
+	middleware.Log.Flush()
+}
+
+// 调用 serve 启动服务，收到 quit 中的信号后关闭 server
+// 停止接收新的连接并最多等待 ShutdownWait 秒让处理中的 Request 完成
+// 只有 serve 本身出错时才返回错误
+func serveGraceful(server *http.Server, serve func() error, quit <-chan os.Signal) error {
+	errc := make(chan error, 1)
+	go func() {
+		errc <- serve()
+	}()
+
+	select {
+	case err := <-errc:
+		return err
+	case sig := <-quit:
+		middleware.Log.Info("收到 %v 信号，dockyard 的 Web 服务开始退出", sig)
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Duration(setting.ShutdownWait)*time.Second)
+		defer cancel()
+
+		if err := server.Shutdown(ctx); err != nil {
+			middleware.Log.Error("dockyard 的 Web 服务退出错误: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// 生成使用配置中超时设置的 http.Server
+func newServer(handler http.Handler) *http.Server {
+	return &http.Server{
+		Handler:           handler,
+		ReadHeaderTimeout: time.Duration(setting.HeaderTimeout) * time.Second,
+		ReadTimeout:       time.Duration(setting.ReadTimeout) * time.Second,
+		WriteTimeout:      time.Duration(setting.WriteTimeout) * time.Second,
+		IdleTimeout:       time.Duration(setting.IdleTimeout) * time.Second,
+	}
+}
+
+// 根据配置生成 HTTPS 服务的 TLS 配置，配置了 httpsclientca 时开启双向认证
+func tlsConfig() (*tls.Config, error) {
+	if setting.HttpsCertFile == "" || setting.HttpsKeyFile == "" {
+		return nil, fmt.Errorf("HTTPS 模式必须同时配置 httpscertfile 和 httpskeyfile")
 	}
+
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if setting.HttpsClientCA != "" {
+		ca, err := ioutil.ReadFile(setting.HttpsClientCA)
+		if err != nil {
+			return nil, fmt.Errorf("读取客户端 CA 证书 %s 错误: %v", setting.HttpsClientCA, err)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("客户端 CA 证书 %s 中没有有效的证书", setting.HttpsClientCA)
+		}
+
+		config.ClientCAs = pool
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return config, nil
+}
+
+func runPprof() {
+	listenaddr := setting.PprofAddress
+	if listenaddr == "" {
+		listenaddr = "127.0.0.1:6060"
+	}
+
+	if err := http.ListenAndServe(listenaddr, pprofMux()); err != nil {
+		fmt.Printf("启动 dockyard 的 pprof 服务错误: %v", err)
+	}
+}
+
+// 使用单独的 ServeMux 注册 pprof 的 Handler，不注册到 Web 服务和 http.DefaultServeMux 上
+func pprofMux() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+
+	return mux
 }
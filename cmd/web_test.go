@@ -0,0 +1,235 @@
+package cmd
+
+import (
+	"crypto/tls"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/Unknwon/macaron"
+
+	"github.com/containerops/dockyard/setting"
+	"github.com/containerops/dockyard/web"
+)
+
+func TestNewServerReadTimeout(t *testing.T) {
+	defer func(header, read int) {
+		setting.HeaderTimeout, setting.ReadTimeout = header, read
+	}(setting.HeaderTimeout, setting.ReadTimeout)
+	setting.HeaderTimeout, setting.ReadTimeout = 0, 1
+
+	readerr := make(chan error, 1)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err := ioutil.ReadAll(r.Body)
+		readerr <- err
+	})
+
+	ts := httptest.NewUnstartedServer(handler)
+	ts.Config = newServer(handler)
+	ts.Start()
+	defer ts.Close()
+
+	conn, err := net.Dial("tcp", ts.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	//声明 10 字节的 Body 但只发送 2 字节，然后停止发送
+	start := time.Now()
+	if _, err := conn.Write([]byte("POST / HTTP/1.1\r\nHost: dockyard\r\nContent-Length: 10\r\n\r\nab")); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case err := <-readerr:
+		if err == nil {
+			t.Fatal("expected body read to fail for a stalled client")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("stalled client was not disconnected by the read timeout")
+	}
+
+	if elapsed := time.Since(start); elapsed < 900*time.Millisecond {
+		t.Fatalf("client disconnected after %v, before the 1s read timeout", elapsed)
+	}
+
+	//服务端读超时后连接应该被关闭
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err := ioutil.ReadAll(conn); err != nil {
+		t.Fatalf("expected connection to be closed by the server, got %v", err)
+	}
+}
+
+func TestServeGraceful(t *testing.T) {
+	defer func(wait int) { setting.ShutdownWait = wait }(setting.ShutdownWait)
+	setting.ShutdownWait = 5
+
+	started, release := make(chan struct{}), make(chan struct{})
+	server := newServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		w.Write([]byte("done"))
+	}))
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+
+	quit := make(chan os.Signal, 1)
+	served := make(chan error, 1)
+	go func() {
+		served <- serveGraceful(server, func() error { return server.Serve(listener) }, quit)
+	}()
+
+	type result struct {
+		body string
+		err  error
+	}
+	inflight := make(chan result, 1)
+	go func() {
+		resp, err := http.Get("http://" + addr + "/")
+		if err != nil {
+			inflight <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		body, err := ioutil.ReadAll(resp.Body)
+		inflight <- result{string(body), err}
+	}()
+
+	<-started
+	quit <- syscall.SIGTERM
+
+	//退出开始后新的连接应该被拒绝
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		conn, err := net.Dial("tcp", addr)
+		if err != nil {
+			break
+		}
+		conn.Close()
+		if time.Now().After(deadline) {
+			t.Fatal("new connections are still accepted during shutdown")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	//处理中的 Request 应该正常完成
+	close(release)
+
+	select {
+	case r := <-inflight:
+		if r.err != nil || r.body != "done" {
+			t.Fatalf("expected in-flight request to complete, got body %q err %v", r.body, r.err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("in-flight request did not complete")
+	}
+
+	select {
+	case err := <-served:
+		if err != nil {
+			t.Fatalf("expected clean shutdown, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("serveGraceful did not return after shutdown")
+	}
+}
+
+func TestPprofNotOnWebHandler(t *testing.T) {
+	defer func(enable bool) { setting.PprofEnable = enable }(setting.PprofEnable)
+
+	for _, enable := range []bool{false, true} {
+		setting.PprofEnable = enable
+
+		m := macaron.New()
+		if err := web.SetDockyardMacaron(m); err != nil {
+			t.Fatal(err)
+		}
+
+		req, _ := http.NewRequest("GET", "/debug/pprof/", nil)
+		resp := httptest.NewRecorder()
+		m.ServeHTTP(resp, req)
+
+		if resp.Code != http.StatusNotFound {
+			t.Errorf("pprof enable=%v: expected 404 from the Web handler, got %d", enable, resp.Code)
+		}
+	}
+
+	req, _ := http.NewRequest("GET", "/debug/pprof/", nil)
+	resp := httptest.NewRecorder()
+	pprofMux().ServeHTTP(resp, req)
+
+	if resp.Code != http.StatusOK {
+		t.Errorf("expected 200 from the pprof mux, got %d", resp.Code)
+	}
+}
+
+func TestTLSConfig(t *testing.T) {
+	defer func(cert, key, ca string) {
+		setting.HttpsCertFile, setting.HttpsKeyFile, setting.HttpsClientCA = cert, key, ca
+	}(setting.HttpsCertFile, setting.HttpsKeyFile, setting.HttpsClientCA)
+
+	empty, err := ioutil.TempFile("", "dockyard-ca")
+	if err != nil {
+		t.Fatal(err)
+	}
+	empty.Close()
+	defer os.Remove(empty.Name())
+
+	const (
+		cert = "../cert/containerops/containerops.crt"
+		key  = "../cert/containerops/containerops.key"
+	)
+
+	cases := []struct {
+		name       string
+		cert       string
+		key        string
+		ca         string
+		ok         bool
+		clientauth tls.ClientAuthType
+	}{
+		{"nothing configured", "", "", "", false, tls.NoClientCert},
+		{"cert only", cert, "", "", false, tls.NoClientCert},
+		{"key only", "", key, "", false, tls.NoClientCert},
+		{"cert and key", cert, key, "", true, tls.NoClientCert},
+		{"missing client ca", cert, key, "../cert/containerops/missing.crt", false, tls.NoClientCert},
+		{"empty client ca", cert, key, empty.Name(), false, tls.NoClientCert},
+		{"client ca", cert, key, cert, true, tls.RequireAndVerifyClientCert},
+	}
+
+	for _, c := range cases {
+		setting.HttpsCertFile, setting.HttpsKeyFile, setting.HttpsClientCA = c.cert, c.key, c.ca
+
+		config, err := tlsConfig()
+		if !c.ok {
+			if err == nil {
+				t.Errorf("%s: expected an error", c.name)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", c.name, err)
+			continue
+		}
+		if config.MinVersion != tls.VersionTLS12 {
+			t.Errorf("%s: expected minimum TLS 1.2, got %x", c.name, config.MinVersion)
+		}
+		if config.ClientAuth != c.clientauth {
+			t.Errorf("%s: expected client auth %v, got %v", c.name, c.clientauth, config.ClientAuth)
+		}
+		if (config.ClientCAs != nil) != (c.clientauth == tls.RequireAndVerifyClientCert) {
+			t.Errorf("%s: unexpected client CA pool %v", c.name, config.ClientCAs)
+		}
+	}
+}
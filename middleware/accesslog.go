@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"io"
+	"time"
+
+	"github.com/Unknwon/macaron"
+
+	"github.com/containerops/dockyard/setting"
+)
+
+func accesslog() macaron.Handler {
+	return accesslogHandler(accesslogWriter(setting.AccessLevel))
+}
+
+// 统计读取的 Request Body 字节数，chunked 编码的 Request 没有 ContentLength
+type countingBody struct {
+	io.ReadCloser
+	n int64
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.n += int64(n)
+	return n, err
+}
+
+func accesslogHandler(write func(format string, v ...interface{})) macaron.Handler {
+	return func(ctx *macaron.Context) {
+		start := time.Now()
+
+		body := &countingBody{}
+		if ctx.Req.Request.Body != nil {
+			body.ReadCloser = ctx.Req.Request.Body
+			ctx.Req.Request.Body = body
+		}
+
+		ctx.Next()
+
+		//Handler 没有写入任何内容时 net/http 会返回 200
+		status := ctx.Resp.Status()
+		if status == 0 {
+			status = 200
+		}
+
+		//remote 使用连接的地址，不使用客户端可以伪造的 X-Real-IP 和 X-Forwarded-For
+		//客户端可以控制的字段都用 %q 输出，避免在日志中伪造字段
+		write("[%s] method=%q uri=%q path=%q remote=%q status=%d reqbytes=%d respbytes=%d latency=%s",
+			RequestID(ctx), ctx.Req.Method, ctx.Req.RequestURI, ctx.Req.Header.Get("Path"), ctx.Req.RemoteAddr,
+			status, body.n, ctx.Resp.Size(), time.Since(start))
+	}
+}
+
+// 根据配置的日志级别返回对应的日志输出函数，默认使用 Info 级别
+func accesslogWriter(level string) func(format string, v ...interface{}) {
+	switch level {
+	case "trace":
+		return Log.Trace
+	case "debug":
+		return Log.Debug
+	case "warn":
+		return Log.Warn
+	default:
+		return Log.Info
+	}
+}
@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Unknwon/macaron"
+)
+
+func TestAccesslog(t *testing.T) {
+	var line string
+	write := func(format string, v ...interface{}) {
+		line = fmt.Sprintf(format, v...)
+	}
+
+	m := macaron.New()
+	m.Use(accesslogHandler(write))
+	m.Post("/upload", func(ctx *macaron.Context) {
+		data, _ := ioutil.ReadAll(ctx.Req.Request.Body)
+		ctx.Resp.WriteHeader(http.StatusCreated)
+		ctx.Resp.Write(data[:3])
+	})
+
+	//没有 Content-Length 的 chunked 上传也要统计 Request 的字节数
+	req, _ := http.NewRequest("POST", "/upload", ioutil.NopCloser(strings.NewReader("0123456789")))
+	req.ContentLength = -1
+	req.RequestURI = "/upload"
+	req.RemoteAddr = "10.0.0.1:5000"
+	req.Header.Set("X-Forwarded-For", "1.2.3.4 status=500 latency=0")
+	req.Header.Set("Path", "/a b")
+	m.ServeHTTP(httptest.NewRecorder(), req)
+
+	for _, field := range []string{
+		`method="POST"`,
+		`uri="/upload"`,
+		`path="/a b"`,
+		`remote="10.0.0.1:5000"`,
+		` status=201 `,
+		` reqbytes=10 `,
+		` respbytes=3 `,
+	} {
+		if !strings.Contains(line, field) {
+			t.Errorf("expected %s in access log line: %s", field, line)
+		}
+	}
+
+	if strings.Contains(line, "1.2.3.4") || strings.Contains(line, "status=500") {
+		t.Errorf("client supplied X-Forwarded-For leaked into access log line: %s", line)
+	}
+}
+
+func TestAccesslogDefaultStatus(t *testing.T) {
+	var line string
+	write := func(format string, v ...interface{}) {
+		line = fmt.Sprintf(format, v...)
+	}
+
+	m := macaron.New()
+	m.Use(accesslogHandler(write))
+	m.Get("/", func() {})
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	m.ServeHTTP(httptest.NewRecorder(), req)
+
+	if !strings.Contains(line, " status=200 ") || !strings.Contains(line, " reqbytes=0 ") {
+		t.Errorf("unexpected access log line: %s", line)
+	}
+}
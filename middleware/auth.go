@@ -0,0 +1,136 @@
+package middleware
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/Unknwon/macaron"
+
+	"github.com/containerops/dockyard/setting"
+)
+
+const (
+	permRead  = "read"
+	permWrite = "write"
+)
+
+// 不需要认证的路径，用于负载均衡的健康检查
+// 目前还没有注册健康检查的路由，注册 /_ping 等路由后需要加入这里
+var authSkipPaths = map[string]bool{}
+
+// 保存 token 和权限的对应关系，收到 SIGHUP 时从 token 文件重新加载
+type tokens struct {
+	sync.RWMutex
+	perms map[string]string
+}
+
+func (t *tokens) load(filename string) error {
+	file, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	perms := make(map[string]string)
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) != 2 || (fields[1] != permRead && fields[1] != permWrite) {
+			return fmt.Errorf("无效的 token 配置: %s", line)
+		}
+
+		perms[fields[0]] = fields[1]
+	}
+
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	t.Lock()
+	t.perms = perms
+	t.Unlock()
+
+	return nil
+}
+
+func (t *tokens) perm(token string) (string, bool) {
+	t.RLock()
+	defer t.RUnlock()
+
+	perm, ok := t.perms[token]
+	return perm, ok
+}
+
+// 启动时 token 文件读取失败返回错误，避免服务启动后所有 Request 都返回 401
+func auth() (macaron.Handler, error) {
+	t := &tokens{perms: make(map[string]string)}
+
+	if err := t.load(setting.AuthTokenFile); err != nil {
+		return nil, fmt.Errorf("读取 token 文件 %s 错误: %v", setting.AuthTokenFile, err)
+	}
+
+	//收到 SIGHUP 时重新加载 token 文件，加载失败时保留原有的 token
+	go func() {
+		sighup := make(chan os.Signal, 1)
+		signal.Notify(sighup, syscall.SIGHUP)
+
+		for range sighup {
+			if err := t.load(setting.AuthTokenFile); err != nil {
+				Log.Error("重新加载 token 文件 %s 错误: %v", setting.AuthTokenFile, err)
+			} else {
+				Log.Info("重新加载 token 文件 %s 成功", setting.AuthTokenFile)
+			}
+		}
+	}()
+
+	return func(ctx *macaron.Context) {
+		if authSkipPaths[ctx.Req.URL.Path] {
+			return
+		}
+
+		//认证的 scheme 不区分大小写
+		fields := strings.SplitN(ctx.Req.Header.Get("Authorization"), " ", 2)
+		if len(fields) != 2 || !strings.EqualFold(fields[0], "Bearer") {
+			ctx.Resp.Header().Set("WWW-Authenticate", "Bearer")
+			authError(ctx, http.StatusUnauthorized, "缺少认证 token")
+			return
+		}
+
+		perm, ok := t.perm(strings.TrimSpace(fields[1]))
+		if !ok {
+			ctx.Resp.Header().Set("WWW-Authenticate", "Bearer")
+			authError(ctx, http.StatusUnauthorized, "无效的认证 token")
+			return
+		}
+
+		//只读的 token 只能访问 GET、HEAD 和 OPTIONS 请求
+		switch ctx.Req.Method {
+		case "GET", "HEAD", "OPTIONS":
+		default:
+			if perm != permWrite {
+				authError(ctx, http.StatusForbidden, "token 没有写权限")
+			}
+		}
+	}, nil
+}
+
+func authError(ctx *macaron.Context, status int, message string) {
+	result, _ := json.Marshal(map[string]string{"error": message})
+
+	ctx.Resp.Header().Set("Content-Type", "application/json")
+	ctx.Resp.WriteHeader(status)
+	ctx.Resp.Write(result)
+}
@@ -0,0 +1,135 @@
+package middleware
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Unknwon/macaron"
+
+	"github.com/containerops/dockyard/setting"
+)
+
+func writeTokenFile(t *testing.T, content string) string {
+	file, err := ioutil.TempFile("", "dockyard-tokens")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	if _, err := file.WriteString(content); err != nil {
+		t.Fatal(err)
+	}
+	return file.Name()
+}
+
+func TestAuth(t *testing.T) {
+	defer func(filename string) { setting.AuthTokenFile = filename }(setting.AuthTokenFile)
+	setting.AuthTokenFile = writeTokenFile(t, "# token permission\nreadtoken read\n\nwritetoken write\n")
+	defer os.Remove(setting.AuthTokenFile)
+
+	handler, err := auth()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := macaron.New()
+	m.Use(handler)
+	m.Get("/", func() {})
+	m.Delete("/", func() {})
+
+	cases := []struct {
+		method        string
+		authorization string
+		status        int
+	}{
+		{"GET", "", http.StatusUnauthorized},
+		{"GET", "Basic readtoken", http.StatusUnauthorized},
+		{"GET", "Bearer unknown", http.StatusUnauthorized},
+		{"GET", "Bearer readtoken", http.StatusOK},
+		{"GET", "bearer readtoken", http.StatusOK},
+		{"DELETE", "Bearer readtoken", http.StatusForbidden},
+		{"DELETE", "Bearer writetoken", http.StatusOK},
+		{"DELETE", "BEARER writetoken", http.StatusOK},
+	}
+
+	for _, c := range cases {
+		req, _ := http.NewRequest(c.method, "/", nil)
+		if c.authorization != "" {
+			req.Header.Set("Authorization", c.authorization)
+		}
+		resp := httptest.NewRecorder()
+		m.ServeHTTP(resp, req)
+
+		if resp.Code != c.status {
+			t.Errorf("%s with %q: expected %d, got %d", c.method, c.authorization, c.status, resp.Code)
+			continue
+		}
+
+		if c.status == http.StatusUnauthorized && resp.Header().Get("WWW-Authenticate") != "Bearer" {
+			t.Errorf("%s with %q: expected WWW-Authenticate: Bearer, got %q", c.method, c.authorization, resp.Header().Get("WWW-Authenticate"))
+		}
+
+		if c.status != http.StatusOK {
+			var body map[string]string
+			if resp.Header().Get("Content-Type") != "application/json" || json.Unmarshal(resp.Body.Bytes(), &body) != nil || body["error"] == "" {
+				t.Errorf("%s with %q: expected JSON error body, got %q", c.method, c.authorization, resp.Body.String())
+			}
+		}
+	}
+}
+
+func TestAuthMissingTokenFile(t *testing.T) {
+	defer func(filename string) { setting.AuthTokenFile = filename }(setting.AuthTokenFile)
+	setting.AuthTokenFile = filepath.Join(os.TempDir(), "dockyard-missing-tokens.conf")
+
+	if _, err := auth(); err == nil {
+		t.Fatal("expected auth to fail when the token file is missing")
+	}
+}
+
+func TestTokensLoad(t *testing.T) {
+	for _, content := range []string{
+		"token",
+		"token admin",
+		"token read extra",
+	} {
+		filename := writeTokenFile(t, content)
+		defer os.Remove(filename)
+
+		tk := &tokens{perms: make(map[string]string)}
+		if err := tk.load(filename); err == nil {
+			t.Errorf("expected load to reject %q", content)
+		}
+	}
+
+	if err := (&tokens{}).load(filepath.Join(os.TempDir(), "dockyard-missing-tokens.conf")); err == nil {
+		t.Error("expected load to fail for a missing file")
+	}
+}
+
+func TestTokensReloadFailureKeepsTokens(t *testing.T) {
+	good, bad := writeTokenFile(t, "readtoken read\n"), writeTokenFile(t, "newtoken write\nbadline\n")
+	defer os.Remove(good)
+	defer os.Remove(bad)
+
+	tk := &tokens{perms: make(map[string]string)}
+	if err := tk.load(good); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := tk.load(bad); err == nil {
+		t.Fatal("expected reload of a bad token file to fail")
+	}
+
+	if perm, ok := tk.perm("readtoken"); !ok || perm != permRead {
+		t.Errorf("expected old token to be kept after failed reload, got %q %v", perm, ok)
+	}
+	if _, ok := tk.perm("newtoken"); ok {
+		t.Error("expected tokens from the failed reload not to be applied")
+	}
+}
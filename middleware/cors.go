@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/Unknwon/macaron"
+
+	"github.com/containerops/dockyard/setting"
+)
+
+// 未配置时 CORS 默认允许的方法和请求头
+var (
+	corsDefaultMethods = []string{"GET", "HEAD", "POST", "PUT", "DELETE"}
+	corsDefaultHeaders = []string{"Content-Type", "Authorization", "Path", "Fragment-Index", "Bytes-Range", "Is-Last"}
+)
+
+// 允许浏览器读取的 Response Header
+var corsExposeHeaders = strings.Join([]string{RequestIDHeader, "Retry-After", "WWW-Authenticate"}, ", ")
+
+func cors() macaron.Handler {
+	methods := setting.CorsMethods
+	if len(methods) == 0 {
+		methods = corsDefaultMethods
+	}
+
+	headers := setting.CorsHeaders
+	if len(headers) == 0 {
+		headers = corsDefaultHeaders
+	}
+
+	allowMethods := strings.Join(methods, ", ")
+	allowHeaders := strings.Join(headers, ", ")
+
+	origins := setting.CorsOrigins
+	if len(origins) == 0 {
+		Log.Warn("开启了 CORS 但没有配置 cors::origins，所有跨域请求都不会被允许")
+	}
+
+	return func(ctx *macaron.Context) {
+		//Response 是否带 CORS 的 Header 取决于 Origin，所有 Response 都要设置 Vary 避免共享缓存返回错误的内容
+		ctx.Resp.Header().Add("Vary", "Origin")
+
+		origin := ctx.Req.Header.Get("Origin")
+		//非跨域请求或不在允许列表中的 Origin 不做处理
+		if origin == "" || !corsAllowOrigin(origins, origin) {
+			return
+		}
+
+		ctx.Resp.Header().Set("Access-Control-Allow-Origin", origin)
+
+		//处理浏览器的 OPTIONS 预检请求，直接返回不再进入路由
+		if ctx.Req.Method == "OPTIONS" && ctx.Req.Header.Get("Access-Control-Request-Method") != "" {
+			ctx.Resp.Header().Set("Access-Control-Allow-Methods", allowMethods)
+			ctx.Resp.Header().Set("Access-Control-Allow-Headers", allowHeaders)
+			ctx.Resp.Header().Set("Access-Control-Max-Age", "600")
+			ctx.Resp.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		ctx.Resp.Header().Set("Access-Control-Expose-Headers", corsExposeHeaders)
+	}
+}
+
+// 检查 origin 是否在允许列表 origins 中，列表中的 * 表示允许任意 Origin
+func corsAllowOrigin(origins []string, origin string) bool {
+	for _, o := range origins {
+		if o == "*" || strings.EqualFold(o, origin) {
+			return true
+		}
+	}
+
+	return false
+}
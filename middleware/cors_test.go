@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Unknwon/macaron"
+
+	"github.com/containerops/dockyard/setting"
+)
+
+func TestCors(t *testing.T) {
+	defer func(origins []string) { setting.CorsOrigins = origins }(setting.CorsOrigins)
+	setting.CorsOrigins = []string{"https://console.example.com"}
+
+	m := macaron.New()
+	m.Use(cors())
+	m.Get("/", func(ctx *macaron.Context) {
+		ctx.Resp.WriteHeader(http.StatusTooManyRequests)
+	})
+
+	cases := []struct {
+		origin string
+		allow  string
+		expose bool
+	}{
+		{"", "", false},
+		{"https://evil.example.com", "", false},
+		{"https://console.example.com", "https://console.example.com", true},
+	}
+
+	for _, c := range cases {
+		req, _ := http.NewRequest("GET", "/", nil)
+		if c.origin != "" {
+			req.Header.Set("Origin", c.origin)
+		}
+		resp := httptest.NewRecorder()
+		m.ServeHTTP(resp, req)
+
+		if vary := resp.Header().Get("Vary"); vary != "Origin" {
+			t.Errorf("origin %q: expected Vary: Origin, got %q", c.origin, vary)
+		}
+		if allow := resp.Header().Get("Access-Control-Allow-Origin"); allow != c.allow {
+			t.Errorf("origin %q: expected Access-Control-Allow-Origin %q, got %q", c.origin, c.allow, allow)
+		}
+		if expose := resp.Header().Get("Access-Control-Expose-Headers"); (expose == corsExposeHeaders) != c.expose {
+			t.Errorf("origin %q: unexpected Access-Control-Expose-Headers %q", c.origin, expose)
+		}
+	}
+}
+
+func TestCorsPreflight(t *testing.T) {
+	defer func(origins []string) { setting.CorsOrigins = origins }(setting.CorsOrigins)
+	setting.CorsOrigins = []string{"*"}
+
+	m := macaron.New()
+	m.Use(cors())
+
+	req, _ := http.NewRequest("OPTIONS", "/api/v1/file", nil)
+	req.Header.Set("Origin", "https://console.example.com")
+	req.Header.Set("Access-Control-Request-Method", "POST")
+	resp := httptest.NewRecorder()
+	m.ServeHTTP(resp, req)
+
+	if resp.Code != http.StatusNoContent {
+		t.Fatalf("expected 204 for preflight, got %d", resp.Code)
+	}
+	if resp.Header().Get("Access-Control-Allow-Methods") == "" || resp.Header().Get("Access-Control-Allow-Headers") == "" {
+		t.Fatalf("expected allow methods and headers on preflight, got %v", resp.Header())
+	}
+}
@@ -30,8 +30,8 @@ func logger() macaron.Handler {
 			Log.Trace("")
 			Log.Trace("----------------------------------------------------------------------------------")
 		}
-		//默认输出 Request 的 Method、 URI 和 Header 的信息
-		Log.Trace("[%s] [%s]", ctx.Req.Method, ctx.Req.RequestURI)
-		Log.Trace("[Header] %v", ctx.Req.Header)
+		//默认输出 Request 的 ID、Method、 URI 和 Header 的信息
+		Log.Trace("[%s] [%s] [%s]", RequestID(ctx), ctx.Req.Method, ctx.Req.RequestURI)
+		Log.Trace("[%s] [Header] %v", RequestID(ctx), ctx.Req.Header)
 	}
 }
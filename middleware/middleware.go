@@ -4,19 +4,51 @@ import (
 	"github.com/Unknwon/macaron"
 
 	_ "github.com/macaron-contrib/session/redis"
+
+	"github.com/containerops/dockyard/setting"
 )
 
-func SetMiddlewares(m *macaron.Macaron) {
+func SetMiddlewares(m *macaron.Macaron) error {
 	//设置静态文件目录，静态文件的访问不进行日志输出
 	m.Use(macaron.Static("static", macaron.StaticOptions{
 		Expires: func() string { return "max-age=0" },
 	}))
 
+	//设置 Request ID 的 Handler 函数，需要在 logger 之前执行
+	m.Use(requestid())
+
 	//设置全局 Logger
 	m.Map(Log)
 	//设置 logger 的 Handler 函数，处理所有 Request 的日志输出
 	m.Use(logger())
 
+	//设置访问日志的 Handler 函数，记录每个 Request 的状态码、流量和耗时
+	if setting.AccessLog {
+		m.Use(accesslog())
+	}
+
+	//设置 CORS 的 Handler 函数，默认不开启
+	//需要在限流和认证之前执行，浏览器才能读取 429 和 401 的 Response
+	if setting.CorsEnable {
+		m.Use(cors())
+	}
+
+	//设置按客户端限流的 Handler 函数，默认不开启
+	if setting.LimitEnable {
+		m.Use(ratelimit())
+	}
+
+	//设置 Token 认证的 Handler 函数，默认不开启
+	if setting.AuthEnable {
+		handler, err := auth()
+		if err != nil {
+			return err
+		}
+		m.Use(handler)
+	}
+
 	//设置 panic 的 Recovery
 	m.Use(macaron.Recovery())
+
+	return nil
 }
@@ -0,0 +1,140 @@
+package middleware
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Unknwon/macaron"
+
+	"github.com/containerops/dockyard/setting"
+)
+
+const (
+	limitDefaultRate  = 50
+	limitDefaultBurst = 100
+
+	//超过 limitIdleTimeout 没有访问的客户端的令牌桶会被清理
+	limitIdleTimeout = 10 * time.Minute
+)
+
+// 单个客户端的令牌桶
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// 按客户端地址分配令牌桶的限流器
+type limiter struct {
+	sync.Mutex
+	rate    float64
+	burst   float64
+	buckets map[string]*bucket
+}
+
+func newLimiter(rate float64, burst int) *limiter {
+	return &limiter{
+		rate:    rate,
+		burst:   float64(burst),
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// 从 key 对应的令牌桶取出一个令牌
+// 成功返回 0，令牌不足时返回需要等待的时间
+func (l *limiter) take(key string, now time.Time) time.Duration {
+	l.Lock()
+	defer l.Unlock()
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0
+	}
+
+	return time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+}
+
+// 清理 idle 时间内没有访问的令牌桶
+func (l *limiter) evict(now time.Time, idle time.Duration) {
+	l.Lock()
+	defer l.Unlock()
+
+	for key, b := range l.buckets {
+		if now.Sub(b.last) > idle {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+func ratelimit() macaron.Handler {
+	rate, burst := setting.LimitRate, setting.LimitBurst
+	if rate <= 0 {
+		rate = limitDefaultRate
+	}
+	if burst <= 0 {
+		burst = limitDefaultBurst
+	}
+
+	l := newLimiter(rate, burst)
+
+	//Unix Socket 模式下 RemoteAddr 中没有客户端 IP，所有客户端会共用一个令牌桶
+	if setting.ListenMode == "unix" && setting.LimitHeader == "" {
+		Log.Warn("Unix Socket 模式下没有配置 ratelimit::header，所有客户端将共用一个限流令牌桶")
+	}
+
+	go func() {
+		for now := range time.Tick(limitIdleTimeout) {
+			l.evict(now, limitIdleTimeout)
+		}
+	}()
+
+	return func(ctx *macaron.Context) {
+		key := limitKey(ctx)
+
+		wait := l.take(key, time.Now())
+		if wait == 0 {
+			return
+		}
+
+		Log.Warn("[%s] 客户端 %s 的访问超过限制", RequestID(ctx), key)
+
+		ctx.Resp.Header().Set("Retry-After", retryAfter(wait))
+		ctx.Resp.WriteHeader(http.StatusTooManyRequests)
+	}
+}
+
+// 返回 Retry-After 的秒数，不足一秒按一秒计算
+func retryAfter(wait time.Duration) string {
+	return fmt.Sprintf("%d", int(math.Ceil(wait.Seconds())))
+}
+
+// 返回限流使用的客户端标识，前端有代理时可以配置从指定的 Header 中读取
+// 只取 Header 中最右边的地址，即可信代理追加的客户端地址，客户端自己填写的地址都在左边
+// 因此只有在前端代理会追加或覆盖该 Header 时才能配置 ratelimit::header
+func limitKey(ctx *macaron.Context) string {
+	if setting.LimitHeader != "" {
+		values := strings.Split(strings.Join(ctx.Req.Header[http.CanonicalHeaderKey(setting.LimitHeader)], ","), ",")
+		if key := strings.TrimSpace(values[len(values)-1]); key != "" {
+			return key
+		}
+	}
+
+	addr := ctx.Req.RemoteAddr
+	if i := strings.LastIndex(addr, ":"); i > -1 {
+		addr = addr[:i]
+	}
+
+	return addr
+}
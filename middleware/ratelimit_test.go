@@ -0,0 +1,116 @@
+package middleware
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLimiterBurst(t *testing.T) {
+	l := newLimiter(1, 3)
+	now := time.Now()
+
+	for i := 0; i < 3; i++ {
+		if wait := l.take("client", now); wait != 0 {
+			t.Fatalf("take %d: expected no wait within burst, got %v", i, wait)
+		}
+	}
+
+	if wait := l.take("client", now); wait == 0 {
+		t.Fatal("expected wait after burst is used up")
+	}
+
+	//其他客户端使用独立的令牌桶
+	if wait := l.take("other", now); wait != 0 {
+		t.Fatalf("expected independent bucket for other client, got wait %v", wait)
+	}
+}
+
+func TestLimiterRefill(t *testing.T) {
+	l := newLimiter(2, 1)
+	now := time.Now()
+
+	if wait := l.take("client", now); wait != 0 {
+		t.Fatalf("expected no wait, got %v", wait)
+	}
+
+	wait := l.take("client", now)
+	if wait != 500*time.Millisecond {
+		t.Fatalf("expected wait 500ms at 2 tokens/s, got %v", wait)
+	}
+
+	if wait := l.take("client", now.Add(500*time.Millisecond)); wait != 0 {
+		t.Fatalf("expected token refilled after 500ms, got wait %v", wait)
+	}
+
+	//长时间空闲后令牌数不超过 burst
+	later := now.Add(time.Hour)
+	if wait := l.take("client", later); wait != 0 {
+		t.Fatalf("expected no wait after idle, got %v", wait)
+	}
+	if wait := l.take("client", later); wait == 0 {
+		t.Fatal("expected refill to be capped at burst")
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	cases := []struct {
+		wait time.Duration
+		want string
+	}{
+		{100 * time.Millisecond, "1"},
+		{time.Second, "1"},
+		{1500 * time.Millisecond, "2"},
+	}
+
+	for _, c := range cases {
+		if got := retryAfter(c.wait); got != c.want {
+			t.Errorf("retryAfter(%v) = %s, want %s", c.wait, got, c.want)
+		}
+	}
+}
+
+func TestLimiterEvict(t *testing.T) {
+	l := newLimiter(1, 1)
+	now := time.Now()
+
+	l.take("idle", now)
+	l.take("active", now.Add(9*time.Minute))
+
+	l.evict(now.Add(11*time.Minute), 10*time.Minute)
+
+	if _, ok := l.buckets["idle"]; ok {
+		t.Error("expected idle bucket to be evicted")
+	}
+	if _, ok := l.buckets["active"]; !ok {
+		t.Error("expected active bucket to be kept")
+	}
+}
+
+func TestLimiterConcurrent(t *testing.T) {
+	l := newLimiter(0.001, 100)
+	now := time.Now()
+
+	var allowed int64
+	var wg sync.WaitGroup
+
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				if l.take("client", now) == 0 {
+					atomic.AddInt64(&allowed, 1)
+				}
+			}
+			l.evict(now, time.Hour)
+		}()
+	}
+
+	wg.Wait()
+
+	if allowed != 100 {
+		t.Fatalf("expected exactly burst (100) requests allowed, got %d", allowed)
+	}
+}
@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/Unknwon/macaron"
+
+	"github.com/containerops/dockyard/utils"
+)
+
+const (
+	RequestIDHeader = "X-Request-Id"
+	requestIDKey    = "RequestID"
+
+	//客户端传入的 Request ID 的最大长度
+	requestIDMaxLen = 128
+)
+
+// 生成 UUID 失败时使用的序号
+var requestIDSeq uint64
+
+func requestid() macaron.Handler {
+	return func(ctx *macaron.Context) {
+		//优先使用客户端或前端代理传入的 Request ID，没有或者格式不合法时生成一个
+		id := ctx.Req.Header.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+
+		ctx.Data[requestIDKey] = id
+		ctx.Resp.Header().Set(RequestIDHeader, id)
+	}
+}
+
+// 返回当前 Request 的 ID，供 Handler 输出日志时使用
+func RequestID(ctx *macaron.Context) string {
+	if id, ok := ctx.Data[requestIDKey].(string); ok {
+		return id
+	}
+
+	return ""
+}
+
+// Request ID 会输出到 Response 和日志中，只接受长度不超过 128 并且由字母、数字、点、下划线和连字符组成的 ID
+func validRequestID(id string) bool {
+	if id == "" || len(id) > requestIDMaxLen {
+		return false
+	}
+
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '.', c == '_', c == '-':
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+// 生成新的 Request ID，读取随机数失败时使用时间和序号生成
+func newRequestID() string {
+	if id, err := utils.UUID(); err == nil {
+		return id
+	}
+
+	return fmt.Sprintf("%x-%x", time.Now().UnixNano(), atomic.AddUint64(&requestIDSeq, 1))
+}
@@ -0,0 +1,88 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Unknwon/macaron"
+)
+
+func TestValidRequestID(t *testing.T) {
+	cases := []struct {
+		id    string
+		valid bool
+	}{
+		{"", false},
+		{"3f2504e0-4f89-41d3-9a0c-0305e82c3301", true},
+		{"build_42.push-7", true},
+		{strings.Repeat("a", 128), true},
+		{strings.Repeat("a", 129), false},
+		{"abc status=200", false},
+		{"abc\nremote=1.2.3.4", false},
+		{"abc\"", false},
+	}
+
+	for _, c := range cases {
+		if got := validRequestID(c.id); got != c.valid {
+			t.Errorf("validRequestID(%q) = %v, want %v", c.id, got, c.valid)
+		}
+	}
+}
+
+func TestNewRequestID(t *testing.T) {
+	id := newRequestID()
+	if !validRequestID(id) {
+		t.Fatalf("generated request id %q is not valid", id)
+	}
+	if id == newRequestID() {
+		t.Fatal("expected generated request ids to differ")
+	}
+}
+
+func TestRequestIDMiddleware(t *testing.T) {
+	var seen string
+
+	m := macaron.New()
+	m.Use(requestid())
+	m.Get("/", func(ctx *macaron.Context) {
+		seen = RequestID(ctx)
+	})
+
+	cases := []struct {
+		incoming string
+		echoed   bool
+	}{
+		{"build_42.push-7", true},
+		{"abc status=200", false},
+		{"", false},
+	}
+
+	for _, c := range cases {
+		seen = ""
+
+		req, _ := http.NewRequest("GET", "/", nil)
+		if c.incoming != "" {
+			req.Header.Set(RequestIDHeader, c.incoming)
+		}
+		resp := httptest.NewRecorder()
+		m.ServeHTTP(resp, req)
+
+		id := resp.Header().Get(RequestIDHeader)
+		if id != seen {
+			t.Errorf("incoming %q: response header %q differs from RequestID(ctx) %q", c.incoming, id, seen)
+		}
+
+		if c.echoed {
+			if id != c.incoming {
+				t.Errorf("incoming %q: expected it to be echoed, got %q", c.incoming, id)
+			}
+			continue
+		}
+
+		if id == "" || id == c.incoming || !validRequestID(id) {
+			t.Errorf("incoming %q: expected a generated request id, got %q", c.incoming, id)
+		}
+	}
+}
@@ -17,15 +17,43 @@ var (
 	ListenMode    string
 	HttpsCertFile string
 	HttpsKeyFile  string
+	HttpsClientCA string
+	ShutdownWait  int
 	LogPath       string
+	CorsEnable    bool
+	CorsOrigins   []string
+	CorsMethods   []string
+	CorsHeaders   []string
+	PprofEnable   bool
+	PprofAddress  string
+	AccessLog     bool
+	AccessLevel   string
+	LimitEnable   bool
+	LimitRate     float64
+	LimitBurst    int
+	LimitHeader   string
+	AuthEnable    bool
+	AuthTokenFile string
+	HeaderTimeout int
+	ReadTimeout   int
+	WriteTimeout  int
+	IdleTimeout   int
 )
 
 func init() {
 	var err error
 
+	//有默认值的配置先设置默认值，配置文件读取失败时也使用这些默认值
+	//读写超时默认不开启以免中断大文件的上传和下载
+	ShutdownWait = 30
+	AccessLog = true
+	HeaderTimeout = 10
+	IdleTimeout = 120
+
 	conf, err = config.NewConfig("ini", "conf/dockyard.conf")
 	if err != nil {
-		fmt.Errorf("读取配置文件 conf/dockyard.conf 错误: %v", err)
+		fmt.Printf("读取配置文件 conf/dockyard.conf 错误: %v", err)
+		return
 	}
 
 	if appname := conf.String("appname"); appname != "" {
@@ -64,7 +92,79 @@ func init() {
 		HttpsKeyFile = httpskeyfile
 	}
 
+	if httpsclientca := conf.String("httpsclientca"); httpsclientca != "" {
+		HttpsClientCA = httpsclientca
+	}
+
+	//shutdownwait 必须大于 0，否则退出时会直接中断处理中的 Request
+	if shutdownwait := conf.DefaultInt("shutdownwait", ShutdownWait); shutdownwait > 0 {
+		ShutdownWait = shutdownwait
+	} else {
+		fmt.Printf("配置 shutdownwait = %d 无效，必须大于 0，使用默认值 %d", shutdownwait, ShutdownWait)
+	}
+
 	if logpath := conf.String("log::filepath"); logpath != "" {
 		LogPath = logpath
 	}
+
+	if corsenable, err := conf.Bool("cors::enable"); err == nil {
+		CorsEnable = corsenable
+	}
+
+	if corsorigins := conf.Strings("cors::origins"); len(corsorigins) > 0 && corsorigins[0] != "" {
+		CorsOrigins = corsorigins
+	}
+
+	if corsmethods := conf.Strings("cors::methods"); len(corsmethods) > 0 && corsmethods[0] != "" {
+		CorsMethods = corsmethods
+	}
+
+	if corsheaders := conf.Strings("cors::headers"); len(corsheaders) > 0 && corsheaders[0] != "" {
+		CorsHeaders = corsheaders
+	}
+
+	if pprofenable, err := conf.Bool("pprof::enable"); err == nil {
+		PprofEnable = pprofenable
+	}
+
+	if pprofaddress := conf.String("pprof::address"); pprofaddress != "" {
+		PprofAddress = pprofaddress
+	}
+
+	//访问日志默认开启，高并发的部署可以在配置中关闭
+	AccessLog = conf.DefaultBool("accesslog::enable", AccessLog)
+
+	if accesslevel := conf.String("accesslog::level"); accesslevel != "" {
+		AccessLevel = accesslevel
+	}
+
+	if limitenable, err := conf.Bool("ratelimit::enable"); err == nil {
+		LimitEnable = limitenable
+	}
+
+	if limitrate, err := conf.Float("ratelimit::rate"); err == nil {
+		LimitRate = limitrate
+	}
+
+	if limitburst, err := conf.Int("ratelimit::burst"); err == nil {
+		LimitBurst = limitburst
+	}
+
+	if limitheader := conf.String("ratelimit::header"); limitheader != "" {
+		LimitHeader = limitheader
+	}
+
+	if authenable, err := conf.Bool("auth::enable"); err == nil {
+		AuthEnable = authenable
+	}
+
+	if authtokenfile := conf.String("auth::tokenfile"); authtokenfile != "" {
+		AuthTokenFile = authtokenfile
+	}
+
+	//超时单位是秒，读超时包含读取 Body 的时间，停滞的客户端由 readheader 和 idle 超时断开
+	HeaderTimeout = conf.DefaultInt("timeout::readheader", HeaderTimeout)
+	ReadTimeout = conf.DefaultInt("timeout::read", ReadTimeout)
+	WriteTimeout = conf.DefaultInt("timeout::write", WriteTimeout)
+	IdleTimeout = conf.DefaultInt("timeout::idle", IdleTimeout)
 }
@@ -0,0 +1,17 @@
+package setting
+
+import (
+	"testing"
+)
+
+// 测试时工作目录下没有 conf/dockyard.conf，所有配置都应该是默认值
+func TestDefaultsWithoutConfig(t *testing.T) {
+	if conf != nil {
+		t.Skip("conf/dockyard.conf is present")
+	}
+
+	if ShutdownWait != 30 || !AccessLog || HeaderTimeout != 10 || IdleTimeout != 120 || ReadTimeout != 0 || WriteTimeout != 0 {
+		t.Fatalf("unexpected defaults: shutdownwait=%d accesslog=%v readheader=%d read=%d write=%d idle=%d",
+			ShutdownWait, AccessLog, HeaderTimeout, ReadTimeout, WriteTimeout, IdleTimeout)
+	}
+}
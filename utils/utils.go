@@ -1,6 +1,8 @@
 package utils
 
 import (
+	"crypto/rand"
+	"fmt"
 	"os"
 )
 
@@ -10,3 +12,16 @@ func Exist(filename string) bool {
 	_, err := os.Stat(filename)
 	return err == nil || os.IsExist(err)
 }
+
+// 生成随机的 UUID (version 4) 字符串，读取随机数失败时返回错误
+func UUID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
@@ -7,11 +7,15 @@ import (
 	"github.com/containerops/dockyard/router"
 )
 
-func SetDockyardMacaron(m *macaron.Macaron) {
+func SetDockyardMacaron(m *macaron.Macaron) error {
 	//设置 Setting
 
 	//设置 Middleware
-	middleware.SetMiddlewares(m)
+	if err := middleware.SetMiddlewares(m); err != nil {
+		return err
+	}
 	//设置 Router
 	router.SetRouters(m)
+
+	return nil
 }